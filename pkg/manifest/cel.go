@@ -0,0 +1,201 @@
+package manifest
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/checker"
+	configv1 "github.com/openshift/api/config/v1"
+)
+
+// CELCostBudget bounds both the statically estimated and the observed
+// runtime cost of an IncludeWithPredicate expression, mirroring the cost
+// limits apiextensions-apiserver enforces on CRD validation rules. Callers
+// that need a different budget may change it before evaluating predicates.
+var CELCostBudget uint64 = 1000000
+
+// predicateEnv declares the variables available to an IncludeWithPredicate
+// expression: the manifest as object, the requested profile, the cluster
+// capabilities status, and the feature gates enabled on the manifest.
+var predicateEnv = mustNewPredicateEnv()
+
+func mustNewPredicateEnv() *cel.Env {
+	env, err := cel.NewEnv(
+		cel.Variable("object", cel.DynType),
+		cel.Variable("profile", cel.StringType),
+		cel.Variable("capabilities", cel.DynType),
+		cel.Variable("featureGates", cel.ListType(cel.StringType)),
+	)
+	if err != nil {
+		panic(fmt.Sprintf("manifest: could not build CEL environment: %v", err))
+	}
+	return env
+}
+
+// predicateCacheKey identifies a compiled predicate by both its source and
+// the cost budget it was compiled against, since CELCostBudget is baked
+// into the compiled program's runtime cost limit: changing CELCostBudget
+// must not silently leave a previously compiled expression running under
+// its old limit.
+type predicateCacheKey struct {
+	expr   string
+	budget uint64
+}
+
+var (
+	predicateCacheMu sync.Mutex
+	predicateCache   = map[predicateCacheKey]cel.Program{}
+)
+
+// predicateSizes bounds the size CEL assumes for each of our variables when
+// estimating cost. Without these, CEL treats every variable as unbounded
+// and the estimated cost of even simple expressions overflows, since none
+// of our variables come from a schema CEL could otherwise size itself.
+var predicateSizes = map[string]checker.SizeEstimate{
+	"object":       {Min: 0, Max: 256},
+	"capabilities": {Min: 0, Max: 64},
+	"featureGates": {Min: 0, Max: 32},
+}
+
+// noopCostEstimator supplies the size hints in predicateSizes and no
+// call-cost hints, so EstimateCost otherwise falls back to CEL's built-in
+// worst-case estimate for every function.
+type noopCostEstimator struct{}
+
+func (noopCostEstimator) EstimateSize(element checker.AstNode) *checker.SizeEstimate {
+	path := element.Path()
+	if len(path) == 0 {
+		return nil
+	}
+	if size, ok := predicateSizes[path[0]]; ok {
+		return &size
+	}
+	return nil
+}
+
+func (noopCostEstimator) EstimateCallCost(function, overloadID string, target *checker.AstNode, args []checker.AstNode) *checker.CallEstimate {
+	return nil
+}
+
+// compilePredicate compiles expr into a cel.Program, caching the result so
+// that repeated calls with the same source under the same CELCostBudget
+// are only compiled once. It
+// returns an error naming expr if the program fails to compile or
+// type-check, or if its statically estimated cost exceeds CELCostBudget.
+func compilePredicate(expr string) (cel.Program, error) {
+	predicateCacheMu.Lock()
+	defer predicateCacheMu.Unlock()
+
+	key := predicateCacheKey{expr: expr, budget: CELCostBudget}
+	if prg, ok := predicateCache[key]; ok {
+		return prg, nil
+	}
+
+	ast, issues := predicateEnv.Compile(expr)
+	if issues != nil && issues.Err() != nil {
+		return nil, fmt.Errorf("invalid predicate %q: %w", expr, issues.Err())
+	}
+
+	estimate, err := predicateEnv.EstimateCost(ast, noopCostEstimator{})
+	if err != nil {
+		return nil, fmt.Errorf("invalid predicate %q: could not estimate cost: %w", expr, err)
+	}
+	if estimate.Max > CELCostBudget {
+		return nil, fmt.Errorf("predicate %q exceeds cost budget: estimated cost %d, budget %d", expr, estimate.Max, CELCostBudget)
+	}
+
+	prg, err := predicateEnv.Program(ast, cel.CostTracking(nil), cel.CostLimit(CELCostBudget))
+	if err != nil {
+		return nil, fmt.Errorf("invalid predicate %q: %w", expr, err)
+	}
+
+	predicateCache[key] = prg
+	return prg, nil
+}
+
+// IncludeWithPredicate behaves like Include, and additionally evaluates expr
+// as a CEL program once every other gate has passed. expr is evaluated with
+// the variables object (this manifest's Obj), profile, capabilities (caps),
+// and featureGates (the feature gates enabled on this manifest). The
+// manifest is excluded unless expr evaluates to the boolean true. expr is
+// compiled once per distinct source string and CELCostBudget, and its cost is checked both at
+// compile time and while it runs, so that a runaway expression cannot be
+// used to exhaust CPU.
+func (m Manifest) IncludeWithPredicate(excludeIdentifier *string, includeTechPreview *bool, profile *string, caps *configv1.ClusterVersionCapabilitiesStatus, expr string) error {
+	if err := m.Include(excludeIdentifier, includeTechPreview, profile, caps); err != nil {
+		return err
+	}
+
+	if expr == "" {
+		return nil
+	}
+
+	prg, err := compilePredicate(expr)
+	if err != nil {
+		return fmt.Errorf("%s: %w", m.id, err)
+	}
+
+	var profileVal string
+	if profile != nil {
+		profileVal = *profile
+	}
+
+	var object interface{}
+	if m.Obj != nil {
+		object = m.Obj.Object
+	}
+
+	out, _, err := prg.Eval(map[string]interface{}{
+		"object":       object,
+		"profile":      profileVal,
+		"capabilities": capabilitiesValue(caps),
+		"featureGates": m.featureGates(),
+	})
+	if err != nil {
+		return fmt.Errorf("%s: predicate %q: %w", m.id, expr, err)
+	}
+
+	include, ok := out.Value().(bool)
+	if !ok {
+		return fmt.Errorf("%s: predicate %q did not evaluate to a bool, got %s", m.id, expr, out.Type().TypeName())
+	}
+	if !include {
+		return fmt.Errorf("%s: excluded by predicate %q", m.id, expr)
+	}
+	return nil
+}
+
+// capabilitiesValue converts caps into the value exposed to an
+// IncludeWithPredicate expression as the capabilities variable, with fields
+// known and enabled (e.g. "'HighAvailability' in capabilities.enabled").
+func capabilitiesValue(caps *configv1.ClusterVersionCapabilitiesStatus) map[string]interface{} {
+	known := []string{}
+	enabled := []string{}
+	if caps != nil {
+		for _, c := range caps.KnownCapabilities {
+			known = append(known, string(c))
+		}
+		for _, c := range caps.EnabledCapabilities {
+			enabled = append(enabled, string(c))
+		}
+	}
+	return map[string]interface{}{
+		"known":   known,
+		"enabled": enabled,
+	}
+}
+
+// featureGates returns the feature gates enabled on m via the
+// release.openshift.io/feature-gate annotation, for exposure to an
+// IncludeWithPredicate expression as the featureGates variable.
+func (m Manifest) featureGates() []string {
+	if m.Obj == nil {
+		return nil
+	}
+	fg, ok := m.Obj.GetAnnotations()["release.openshift.io/feature-gate"]
+	if !ok || fg == "" {
+		return nil
+	}
+	return []string{fg}
+}
@@ -0,0 +1,162 @@
+package manifest
+
+import (
+	"strings"
+	"testing"
+
+	configv1 "github.com/openshift/api/config/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const predicateConfigMapYAML = `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: a-config
+  namespace: default
+  annotations:
+    release.openshift.io/feature-gate: TechPreviewNoUpgrade
+data:
+  color: red
+`
+
+func parsePredicateManifest(t *testing.T) Manifest {
+	t.Helper()
+	manifests, err := ParseManifests(strings.NewReader(predicateConfigMapYAML))
+	require.NoError(t, err)
+	require.Len(t, manifests, 1)
+	return manifests[0]
+}
+
+func TestIncludeWithPredicate(t *testing.T) {
+	m := parsePredicateManifest(t)
+
+	t.Run("true predicate includes", func(t *testing.T) {
+		err := m.IncludeWithPredicate(nil, nil, nil, nil, `object.metadata.name == "a-config"`)
+		assert.NoError(t, err)
+	})
+
+	t.Run("false predicate excludes", func(t *testing.T) {
+		err := m.IncludeWithPredicate(nil, nil, nil, nil, `object.metadata.name == "other"`)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "excluded by predicate")
+	})
+
+	t.Run("empty expr is a no-op", func(t *testing.T) {
+		assert.NoError(t, m.IncludeWithPredicate(nil, nil, nil, nil, ""))
+	})
+
+	t.Run("profile and capabilities are visible", func(t *testing.T) {
+		manifests, err := ParseManifests(strings.NewReader(`
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: a-config
+  annotations:
+    include.release.openshift.io/self-managed: "true"
+data:
+  color: red
+`))
+		require.NoError(t, err)
+		require.Len(t, manifests, 1)
+		gated := manifests[0]
+
+		profile := "self-managed"
+		caps := &configv1.ClusterVersionCapabilitiesStatus{
+			KnownCapabilities:   []configv1.ClusterVersionCapability{"baremetal"},
+			EnabledCapabilities: []configv1.ClusterVersionCapability{"baremetal"},
+		}
+		err = gated.IncludeWithPredicate(nil, nil, &profile, caps,
+			`profile == "self-managed" && "baremetal" in capabilities.enabled`)
+		assert.NoError(t, err)
+
+		err = gated.IncludeWithPredicate(nil, nil, &profile, caps,
+			`profile == "other-profile"`)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "excluded by predicate")
+	})
+
+	t.Run("feature gates are visible", func(t *testing.T) {
+		includeTechPreview := true
+		err := m.IncludeWithPredicate(nil, &includeTechPreview, nil, nil,
+			`"TechPreviewNoUpgrade" in featureGates`)
+		assert.NoError(t, err)
+	})
+
+	t.Run("non-bool result is an error", func(t *testing.T) {
+		err := m.IncludeWithPredicate(nil, nil, nil, nil, `object.metadata.name`)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "did not evaluate to a bool")
+	})
+
+	t.Run("invalid expression names the predicate and resource", func(t *testing.T) {
+		err := m.IncludeWithPredicate(nil, nil, nil, nil, `this is not cel`)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "this is not cel")
+		assert.Contains(t, err.Error(), `Kind: "ConfigMap"`)
+	})
+
+	t.Run("other gates still apply first", func(t *testing.T) {
+		exclude := "test"
+		excluded := parsePredicateManifestWithExclude(t)
+		err := excluded.IncludeWithPredicate(&exclude, nil, nil, nil, `true`)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "exclude.release.openshift.io/test=true")
+	})
+}
+
+func parsePredicateManifestWithExclude(t *testing.T) Manifest {
+	t.Helper()
+	manifests, err := ParseManifests(strings.NewReader(`
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: a-config
+  annotations:
+    exclude.release.openshift.io/test: "true"
+data:
+  color: red
+`))
+	require.NoError(t, err)
+	require.Len(t, manifests, 1)
+	return manifests[0]
+}
+
+func TestCompilePredicateCostBudget(t *testing.T) {
+	original := CELCostBudget
+	defer func() { CELCostBudget = original }()
+
+	const expr = `1 == 1`
+
+	CELCostBudget = 0
+	_, err := compilePredicate(expr)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "exceeds cost budget")
+
+	// Raising the budget must re-admit the same expression rather than
+	// reusing a cached rejection keyed only on expr.
+	CELCostBudget = original
+	_, err = compilePredicate(expr)
+	assert.NoError(t, err)
+}
+
+func TestCompilePredicateCachesPerBudget(t *testing.T) {
+	original := CELCostBudget
+	defer func() { CELCostBudget = original }()
+
+	const expr = `1 == 1`
+
+	first, err := compilePredicate(expr)
+	require.NoError(t, err)
+	second, err := compilePredicate(expr)
+	require.NoError(t, err)
+	assert.Same(t, first, second, "identical expressions compiled under the same budget should reuse the cached program")
+
+	// Lowering the budget after first compiling expr must not silently
+	// leave it running under the old, higher limit.
+	CELCostBudget = original - 1
+	third, err := compilePredicate(expr)
+	require.NoError(t, err)
+	assert.NotSame(t, first, third, "changing the budget must compile a fresh program, not reuse one compiled under a different budget")
+}
@@ -0,0 +1,135 @@
+package manifest
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// key returns a string identifying the resource independent of the
+// parenthesized, human-oriented format resourceId.String() produces, for
+// use as a map key.
+func (r resourceId) key() string {
+	return strings.Join([]string{r.Group, r.Kind, r.Namespace, r.Name}, "/")
+}
+
+// Digest returns a stable "sha256:<hex>" digest of the manifest's canonical
+// JSON representation (Raw). The result is cached on the Manifest so
+// repeated calls, and downstream no-op-apply checks, are cheap.
+func (m *Manifest) Digest() string {
+	if m.digest == "" {
+		sum := sha256.Sum256(m.Raw)
+		m.digest = fmt.Sprintf("sha256:%x", sum)
+	}
+	return m.digest
+}
+
+// bundleDigest returns the sha256 sum of the concatenation of every
+// manifest's Digest(), in order. It is the payload VerifyBundle checks
+// signature against.
+func bundleDigest(manifests []Manifest) [sha256.Size]byte {
+	h := sha256.New()
+	for i := range manifests {
+		h.Write([]byte(manifests[i].Digest()))
+	}
+	var sum [sha256.Size]byte
+	copy(sum[:], h.Sum(nil))
+	return sum
+}
+
+// ManifestsFromFilesWithChecksums behaves like ManifestsFromFiles, except
+// it fails closed unless every file's content can be verified against
+// expected. expected may supply a digest for a whole file (keyed by its
+// path, as passed in files) or for an individual resource within it (keyed
+// by resourceId.key(), i.e. "Group/Kind/Namespace/Name"); a file digest
+// takes precedence when both could apply. Any file or resource without a
+// matching, correct digest causes the whole call to fail, though the
+// manifests that did verify are still returned alongside the error.
+func ManifestsFromFilesWithChecksums(files []string, expected map[string]string) ([]Manifest, error) {
+	var manifests []Manifest
+	var errs []error
+
+	for _, p := range files {
+		ms, err := manifestsFromPath(p)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		if want, ok := expected[p]; ok {
+			if got := bundleDigestHex(ms); got != want {
+				errs = append(errs, fmt.Errorf("%s: computed digest %s does not match expected digest %s", p, got, want))
+				continue
+			}
+			manifests = append(manifests, ms...)
+			continue
+		}
+
+		for i := range ms {
+			m := ms[i]
+			want, ok := expected[m.id.key()]
+			if !ok {
+				errs = append(errs, fmt.Errorf("%s: no expected checksum for file or for resource %s", p, m.id))
+				continue
+			}
+			if got := m.Digest(); got != want {
+				errs = append(errs, fmt.Errorf("%s: computed digest %s does not match expected digest %s", m.id, got, want))
+				continue
+			}
+			manifests = append(manifests, m)
+		}
+	}
+
+	if err := checkDuplicate(manifests); err != nil {
+		errs = append(errs, err)
+	}
+
+	if len(errs) == 0 {
+		return manifests, nil
+	}
+	msgs := make([]string, 0, len(errs))
+	for _, err := range errs {
+		msgs = append(msgs, err.Error())
+	}
+	return manifests, errors.New(strings.Join(msgs, "\n"))
+}
+
+// bundleDigestHex formats bundleDigest(manifests) the same way Digest()
+// formats a single manifest's digest.
+func bundleDigestHex(manifests []Manifest) string {
+	sum := bundleDigest(manifests)
+	return fmt.Sprintf("sha256:%x", sum)
+}
+
+// VerifyBundle checks that signature is a valid detached signature, by
+// pub, over the sha256 digest of the concatenation of every manifest's
+// Digest(), in order. It supports RSA (PKCS#1 v1.5), ECDSA, and Ed25519
+// public keys, and returns an error for any other key type or for an
+// invalid signature.
+func VerifyBundle(manifests []Manifest, signature []byte, pub crypto.PublicKey) error {
+	sum := bundleDigest(manifests)
+
+	switch key := pub.(type) {
+	case *rsa.PublicKey:
+		if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, sum[:], signature); err != nil {
+			return fmt.Errorf("invalid signature: %w", err)
+		}
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(key, sum[:], signature) {
+			return fmt.Errorf("invalid signature")
+		}
+	case ed25519.PublicKey:
+		if !ed25519.Verify(key, sum[:], signature) {
+			return fmt.Errorf("invalid signature")
+		}
+	default:
+		return fmt.Errorf("unsupported public key type %T", pub)
+	}
+
+	return nil
+}
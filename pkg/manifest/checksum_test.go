@@ -0,0 +1,121 @@
+package manifest
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const configMapYAML = `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: a-config
+  namespace: default
+data:
+  color: red
+`
+
+func TestDigest(t *testing.T) {
+	manifests, err := ParseManifests(strings.NewReader(configMapYAML))
+	require.NoError(t, err)
+	require.Len(t, manifests, 1)
+
+	digest := manifests[0].Digest()
+	assert.True(t, strings.HasPrefix(digest, "sha256:"))
+	assert.Equal(t, digest, manifests[0].Digest(), "Digest should be stable across calls")
+
+	other, err := ParseManifests(strings.NewReader(configMapYAML))
+	require.NoError(t, err)
+	assert.Equal(t, digest, other[0].Digest(), "Digest should only depend on Raw")
+}
+
+func writeFile(t *testing.T, dir, name, contents string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	require.NoError(t, ioutil.WriteFile(path, []byte(contents), 0644))
+	return path
+}
+
+func TestManifestsFromFilesWithChecksums(t *testing.T) {
+	dir := t.TempDir()
+	path := writeFile(t, dir, "configmap.yaml", configMapYAML)
+
+	manifests, err := ParseManifests(strings.NewReader(configMapYAML))
+	require.NoError(t, err)
+	fileDigest := bundleDigestHex(manifests)
+	resourceDigest := manifests[0].Digest()
+
+	t.Run("matching file digest", func(t *testing.T) {
+		got, err := ManifestsFromFilesWithChecksums([]string{path}, map[string]string{path: fileDigest})
+		require.NoError(t, err)
+		assert.Len(t, got, 1)
+	})
+
+	t.Run("matching resource digest", func(t *testing.T) {
+		got, err := ManifestsFromFilesWithChecksums([]string{path}, map[string]string{manifests[0].id.key(): resourceDigest})
+		require.NoError(t, err)
+		assert.Len(t, got, 1)
+	})
+
+	t.Run("mismatched digest fails closed", func(t *testing.T) {
+		_, err := ManifestsFromFilesWithChecksums([]string{path}, map[string]string{path: "sha256:deadbeef"})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "does not match expected digest")
+	})
+
+	t.Run("missing digest fails closed", func(t *testing.T) {
+		_, err := ManifestsFromFilesWithChecksums([]string{path}, map[string]string{})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "no expected checksum")
+	})
+}
+
+func TestVerifyBundle(t *testing.T) {
+	manifests, err := ParseManifests(strings.NewReader(configMapYAML))
+	require.NoError(t, err)
+	sum := bundleDigest(manifests)
+
+	t.Run("rsa", func(t *testing.T) {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		require.NoError(t, err)
+		sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, sum[:])
+		require.NoError(t, err)
+		assert.NoError(t, VerifyBundle(manifests, sig, &key.PublicKey))
+	})
+
+	t.Run("ecdsa", func(t *testing.T) {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+		sig, err := ecdsa.SignASN1(rand.Reader, key, sum[:])
+		require.NoError(t, err)
+		assert.NoError(t, VerifyBundle(manifests, sig, &key.PublicKey))
+	})
+
+	t.Run("ed25519", func(t *testing.T) {
+		pub, priv, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+		sig := ed25519.Sign(priv, sum[:])
+		assert.NoError(t, VerifyBundle(manifests, sig, pub))
+	})
+
+	t.Run("invalid signature", func(t *testing.T) {
+		pub, _, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+		assert.Error(t, VerifyBundle(manifests, []byte("not-a-signature"), pub))
+	})
+
+	t.Run("unsupported key type", func(t *testing.T) {
+		assert.Error(t, VerifyBundle(manifests, nil, "not-a-key"))
+	})
+}
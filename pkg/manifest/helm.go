@@ -0,0 +1,88 @@
+package manifest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/engine"
+)
+
+// isHelmChartDir returns true if dir looks like the root of a Helm chart,
+// i.e. it contains a Chart.yaml.
+func isHelmChartDir(dir string) bool {
+	_, err := os.Stat(filepath.Join(dir, "Chart.yaml"))
+	return err == nil
+}
+
+// ManifestsFromHelmChart renders the Helm chart rooted at chartPath with
+// the given values and parses the rendered templates through
+// ParseManifests, so duplicate detection, Include, and
+// GetManifestCapabilities behave identically to manifests loaded from
+// plain YAML. chartPath may point at a chart directory or a packaged
+// ".tgz" archive.
+func ManifestsFromHelmChart(chartPath string, values map[string]interface{}) ([]Manifest, error) {
+	chrt, err := loader.Load(chartPath)
+	if err != nil {
+		return nil, fmt.Errorf("error loading helm chart %s: %w", chartPath, err)
+	}
+	return manifestsFromChart(chrt, values)
+}
+
+// ManifestsFromChartArchive renders a packaged Helm chart archive
+// (".tgz") the same way ManifestsFromHelmChart renders a chart directory.
+func ManifestsFromChartArchive(archivePath string, values map[string]interface{}) ([]Manifest, error) {
+	chrt, err := loader.LoadFile(archivePath)
+	if err != nil {
+		return nil, fmt.Errorf("error loading helm chart archive %s: %w", archivePath, err)
+	}
+	return manifestsFromChart(chrt, values)
+}
+
+// manifestsFromChart renders chrt with values and parses every YAML
+// template it produces through ParseManifests.
+func manifestsFromChart(chrt *chart.Chart, values map[string]interface{}) ([]Manifest, error) {
+	renderValues, err := chartutil.ToRenderValues(chrt, values, chartutil.ReleaseOptions{Name: chrt.Name()}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error computing render values for chart %s: %w", chrt.Name(), err)
+	}
+
+	rendered, err := engine.Render(chrt, renderValues)
+	if err != nil {
+		return nil, fmt.Errorf("error rendering chart %s: %w", chrt.Name(), err)
+	}
+
+	names := make([]string, 0, len(rendered))
+	for name := range rendered {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var manifests []Manifest
+	for _, name := range names {
+		content := rendered[name]
+		ext := filepath.Ext(name)
+		if ext != ".yaml" && ext != ".yml" {
+			continue
+		}
+		if len(strings.TrimSpace(content)) == 0 {
+			continue
+		}
+
+		ms, err := ParseManifests(strings.NewReader(content))
+		if err != nil {
+			return nil, fmt.Errorf("error parsing rendered template %s: %w", name, err)
+		}
+		manifests = append(manifests, ms...)
+	}
+
+	if err := checkDuplicate(manifests); err != nil {
+		return manifests, err
+	}
+	return manifests, nil
+}
@@ -0,0 +1,87 @@
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+)
+
+// buildHelmChartDir lays out a minimal chart at root and returns root, with
+// templates written in reverse of their sorted name order, so that tests
+// only see a-config before z-config if rendering sorts them by path.
+func buildHelmChartDir(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+
+	writeFile(t, root, "Chart.yaml", `
+apiVersion: v2
+name: a-chart
+version: 0.1.0
+`)
+
+	templatesDir := filepath.Join(root, "templates")
+	require.NoError(t, os.MkdirAll(templatesDir, 0755))
+
+	writeFile(t, templatesDir, "z-configmap.yaml", `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: z-config
+data:
+  color: red
+`)
+	writeFile(t, templatesDir, "a-configmap.yaml", `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: a-config
+data:
+  color: blue
+`)
+	return root
+}
+
+func TestManifestsFromHelmChart(t *testing.T) {
+	root := buildHelmChartDir(t)
+
+	manifests, err := ManifestsFromHelmChart(root, nil)
+	require.NoError(t, err)
+	require.Len(t, manifests, 2)
+
+	// Templates are rendered in sorted-path order (a-configmap.yaml before
+	// z-configmap.yaml), regardless of the nondeterministic order
+	// engine.Render returns them in.
+	assert.Equal(t, "a-config", manifests[0].Obj.GetName())
+	assert.Equal(t, "z-config", manifests[1].Obj.GetName())
+}
+
+func TestManifestsFromFilesHelmChartDir(t *testing.T) {
+	root := buildHelmChartDir(t)
+
+	manifests, err := ManifestsFromFiles([]string{root})
+	require.NoError(t, err)
+	require.Len(t, manifests, 2)
+	assert.Equal(t, "a-config", manifests[0].Obj.GetName())
+	assert.Equal(t, "z-config", manifests[1].Obj.GetName())
+}
+
+func TestManifestsFromChartArchive(t *testing.T) {
+	root := buildHelmChartDir(t)
+	chrt, err := loader.LoadDir(root)
+	require.NoError(t, err)
+
+	archiveDir := t.TempDir()
+	archivePath, err := chartutil.Save(chrt, archiveDir)
+	require.NoError(t, err)
+
+	manifests, err := ManifestsFromChartArchive(archivePath, nil)
+	require.NoError(t, err)
+	require.Len(t, manifests, 2)
+	assert.Equal(t, "a-config", manifests[0].Obj.GetName())
+	assert.Equal(t, "z-config", manifests[1].Obj.GetName())
+}
@@ -0,0 +1,49 @@
+package manifest
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"sigs.k8s.io/kustomize/api/krusty"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+)
+
+// isKustomizationDir returns true if dir looks like the root of a kustomize
+// overlay or base, i.e. it contains a kustomization.yaml, kustomization.yml,
+// or Kustomization file.
+func isKustomizationDir(dir string) bool {
+	for _, name := range []string{"kustomization.yaml", "kustomization.yml", "Kustomization"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// ManifestsFromKustomization renders the kustomize overlay or base rooted
+// at root in-process and parses the resulting resources through
+// ParseManifests, so duplicate detection, Include, and
+// GetManifestCapabilities behave identically to manifests loaded from
+// plain YAML. Bases, generators, and patches referenced by root are
+// honored the same way the kustomize CLI would honor them.
+func ManifestsFromKustomization(root string) ([]Manifest, error) {
+	fSys := filesys.MakeFsOnDisk()
+
+	resMap, err := krusty.MakeKustomizer(krusty.MakeDefaultOptions()).Run(fSys, root)
+	if err != nil {
+		return nil, fmt.Errorf("error rendering kustomization %s: %w", root, err)
+	}
+
+	rendered, err := resMap.AsYaml()
+	if err != nil {
+		return nil, fmt.Errorf("error rendering kustomization %s: %w", root, err)
+	}
+
+	manifests, err := ParseManifests(bytes.NewReader(rendered))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing kustomization %s: %w", root, err)
+	}
+	return manifests, nil
+}
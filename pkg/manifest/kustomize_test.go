@@ -0,0 +1,53 @@
+package manifest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManifestsFromKustomization(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, root, "configmap.yaml", `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: a-config
+data:
+  color: red
+`)
+	writeFile(t, root, "kustomization.yaml", `
+resources:
+- configmap.yaml
+namespace: overlaid
+`)
+
+	manifests, err := ManifestsFromKustomization(root)
+	require.NoError(t, err)
+	require.Len(t, manifests, 1)
+	assert.Equal(t, "a-config", manifests[0].Obj.GetName())
+	assert.Equal(t, "overlaid", manifests[0].Obj.GetNamespace())
+}
+
+func TestManifestsFromFilesKustomizationDir(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, root, "configmap.yaml", `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: a-config
+data:
+  color: red
+`)
+	writeFile(t, root, "kustomization.yaml", `
+resources:
+- configmap.yaml
+namePrefix: prefixed-
+`)
+
+	manifests, err := ManifestsFromFiles([]string{root})
+	require.NoError(t, err)
+	require.Len(t, manifests, 1)
+	assert.Equal(t, "prefixed-a-config", manifests[0].Obj.GetName())
+}
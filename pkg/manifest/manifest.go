@@ -0,0 +1,344 @@
+// Package manifest holds Manifest, a Kubernetes resource as parsed from a
+// release payload, along with the helpers used to parse, order, and filter
+// collections of manifests before they are applied to a cluster.
+package manifest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	configv1 "github.com/openshift/api/config/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/yaml"
+)
+
+// CapabilityAnnotation is the annotation used on a manifest to gate it on
+// one or more cluster capabilities. Its value is a list of capability
+// names joined by "+", e.g. "cap1+cap2".
+const CapabilityAnnotation = "capability.openshift.io/name"
+
+// resourceId uniquely identifies a Kubernetes resource within a release
+// payload.
+type resourceId struct {
+	Group     string
+	Kind      string
+	Namespace string
+	Name      string
+}
+
+func (r resourceId) String() string {
+	return fmt.Sprintf("(Group: %q Kind: %q Namespace: %q Name: %q)", r.Group, r.Kind, r.Namespace, r.Name)
+}
+
+// Manifest stores Kubernetes object in Raw as well as in Unstructured
+// format.
+type Manifest struct {
+	// id is the resourceId for this manifest, used for duplicate detection
+	// and equality checks.
+	id resourceId
+
+	// Raw is the raw bytes of the manifest, as canonical JSON.
+	Raw []byte
+
+	// GVK is the GroupVersionKind of the manifest.
+	GVK schema.GroupVersionKind
+
+	// Obj is the Unstructured representation of the manifest.
+	Obj *unstructured.Unstructured
+
+	// digest caches the result of Digest(), once computed.
+	digest string
+}
+
+// manifestMetadata is the subset of a Kubernetes resource needed to compute
+// its resourceId.
+type manifestMetadata struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Metadata   struct {
+		Name      string `json:"name"`
+		Namespace string `json:"namespace"`
+	} `json:"metadata"`
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface, populating Raw,
+// GVK, id, and Obj from the given canonical JSON document.
+func (m *Manifest) UnmarshalJSON(in []byte) error {
+	if m == nil {
+		return errors.New("Manifest: UnmarshalJSON on nil pointer")
+	}
+
+	m.Raw = append([]byte(nil), in...)
+
+	var meta manifestMetadata
+	if err := json.Unmarshal(in, &meta); err != nil {
+		return err
+	}
+
+	gv, err := schema.ParseGroupVersion(meta.APIVersion)
+	if err != nil {
+		return fmt.Errorf("could not parse apiVersion: %w", err)
+	}
+	m.GVK = gv.WithKind(meta.Kind)
+	m.id = resourceId{
+		Group:     m.GVK.Group,
+		Kind:      m.GVK.Kind,
+		Namespace: meta.Metadata.Namespace,
+		Name:      meta.Metadata.Name,
+	}
+
+	if m.id.Kind == "" || m.id.Name == "" {
+		return fmt.Errorf("manifest must contain kubernetes required fields kind and name: %s", string(in))
+	}
+
+	u := &unstructured.Unstructured{}
+	if err := json.Unmarshal(in, &u.Object); err != nil {
+		return fmt.Errorf("unable to decode manifest: %w", err)
+	}
+	m.Obj = u
+
+	return nil
+}
+
+// SameResourceID returns true if the two manifests identify the same
+// Kubernetes resource.
+func (m Manifest) SameResourceID(other Manifest) bool {
+	return m.id == other.id
+}
+
+// ParseManifests parses a YAML or JSON document that may contain one or
+// more Kubernetes resources separated by "---" and returns the Manifest
+// for each. It returns an error if any resource is missing its required
+// kind/name fields, or if two resources share the same
+// Group/Kind/Namespace/Name identity.
+func ParseManifests(r io.Reader) ([]Manifest, error) {
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(r))
+	var manifests []Manifest
+	for {
+		raw, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return manifests, err
+		}
+
+		if len(bytes.TrimSpace(raw)) == 0 {
+			continue
+		}
+
+		m := Manifest{}
+		if err := yaml.Unmarshal(raw, &m); err != nil {
+			return manifests, err
+		}
+		if err := validateOrdering(m); err != nil {
+			return manifests, fmt.Errorf("invalid manifest: %w", err)
+		}
+		manifests = append(manifests, m)
+	}
+
+	if err := checkDuplicate(manifests); err != nil {
+		return manifests, err
+	}
+	return manifests, nil
+}
+
+// checkDuplicate returns an error describing every resourceId that appears
+// more than once in manifests, or nil if there are no duplicates.
+func checkDuplicate(manifests []Manifest) error {
+	seen := map[resourceId]struct{}{}
+	var duplicates []string
+	for _, m := range manifests {
+		if _, ok := seen[m.id]; ok {
+			duplicates = append(duplicates, fmt.Sprintf("duplicate resource: %s", m.id))
+			continue
+		}
+		seen[m.id] = struct{}{}
+	}
+	if len(duplicates) == 0 {
+		return nil
+	}
+	return errors.New(strings.Join(duplicates, "\n"))
+}
+
+// ManifestsFromFiles reads and parses the manifests found at each of the
+// given paths, in order, and returns their combined list. A path pointing
+// at a directory that contains a kustomization file is rendered via
+// ManifestsFromKustomization; otherwise a directory that contains a
+// Chart.yaml is rendered as a Helm chart via ManifestsFromHelmChart, and
+// any other directory is an error. Any other path is parsed as a plain
+// YAML/JSON manifest file. If any file fails to parse, or a resource appears more
+// than once across all files, an error describing every such problem is
+// returned alongside whatever manifests could be parsed.
+func ManifestsFromFiles(files []string) ([]Manifest, error) {
+	var manifests []Manifest
+	var errs []error
+
+	for _, p := range files {
+		ms, err := manifestsFromPath(p)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		manifests = append(manifests, ms...)
+	}
+
+	if err := checkDuplicate(manifests); err != nil {
+		errs = append(errs, err)
+	}
+
+	if len(errs) == 0 {
+		return manifests, nil
+	}
+
+	msgs := make([]string, 0, len(errs))
+	for _, err := range errs {
+		msgs = append(msgs, err.Error())
+	}
+	return manifests, errors.New(strings.Join(msgs, "\n"))
+}
+
+// manifestsFromPath parses the manifests at a single path, delegating to
+// ManifestsFromKustomization when the path is a directory containing a
+// kustomization.yaml, or to ManifestsFromHelmChart when it contains a
+// Chart.yaml instead.
+func manifestsFromPath(p string) ([]Manifest, error) {
+	info, err := os.Stat(p)
+	if err != nil {
+		return nil, fmt.Errorf("error opening %s: %w", p, err)
+	}
+
+	if info.IsDir() {
+		switch {
+		case isKustomizationDir(p):
+			return ManifestsFromKustomization(p)
+		case isHelmChartDir(p):
+			ms, err := ManifestsFromHelmChart(p, nil)
+			if err != nil {
+				return nil, fmt.Errorf("error rendering chart %s: %w", p, err)
+			}
+			return ms, nil
+		}
+		return nil, fmt.Errorf("%s is a directory", p)
+	}
+
+	file, err := os.Open(p)
+	if err != nil {
+		return nil, fmt.Errorf("error opening %s: %w", p, err)
+	}
+	defer file.Close()
+
+	ms, err := ParseManifests(file)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing %s: %w", p, err)
+	}
+	return ms, nil
+}
+
+// Include returns nil if the manifest passes every applicable gate, or an
+// error describing the first failing gate otherwise. A nil exclude,
+// includeTechPreview, profile, or caps skips the corresponding gate. A
+// manifest with no annotations at all is always rejected.
+func (m Manifest) Include(excludeIdentifier *string, includeTechPreview *bool, profile *string, caps *configv1.ClusterVersionCapabilitiesStatus) error {
+	if m.Obj == nil {
+		return fmt.Errorf("no annotations")
+	}
+	annotations := m.Obj.GetAnnotations()
+	if annotations == nil {
+		return fmt.Errorf("no annotations")
+	}
+
+	if excludeIdentifier != nil {
+		excludeAnnotation := fmt.Sprintf("exclude.release.openshift.io/%s", *excludeIdentifier)
+		if v, ok := annotations[excludeAnnotation]; ok && v == "true" {
+			return fmt.Errorf("%s=true", excludeAnnotation)
+		}
+	}
+
+	if profile != nil {
+		includeAnnotation := fmt.Sprintf("include.release.openshift.io/%s", *profile)
+		if v, ok := annotations[includeAnnotation]; !ok || v != "true" {
+			return fmt.Errorf("%s unset", includeAnnotation)
+		}
+	}
+
+	if includeTechPreview != nil {
+		if fg, ok := annotations["release.openshift.io/feature-gate"]; ok {
+			switch fg {
+			case "TechPreviewNoUpgrade":
+				if !*includeTechPreview {
+					return fmt.Errorf("tech-preview excluded, and release.openshift.io/feature-gate=%s", fg)
+				}
+			default:
+				return fmt.Errorf("unrecognized value release.openshift.io/feature-gate=%s", fg)
+			}
+		}
+	}
+
+	if caps != nil {
+		if err := checkCapabilities(annotations, caps); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkCapabilities returns an error naming every capability required by
+// the manifest's CapabilityAnnotation that is known but not enabled.
+func checkCapabilities(annotations map[string]string, caps *configv1.ClusterVersionCapabilitiesStatus) error {
+	required, ok := annotations[CapabilityAnnotation]
+	if !ok || len(required) == 0 {
+		return nil
+	}
+
+	known := map[string]struct{}{}
+	for _, c := range caps.KnownCapabilities {
+		known[string(c)] = struct{}{}
+	}
+	enabled := map[string]struct{}{}
+	for _, c := range caps.EnabledCapabilities {
+		enabled[string(c)] = struct{}{}
+	}
+
+	var disabled []string
+	for _, name := range strings.Split(required, "+") {
+		if _, isKnown := known[name]; !isKnown {
+			continue
+		}
+		if _, isEnabled := enabled[name]; !isEnabled {
+			disabled = append(disabled, name)
+		}
+	}
+	if len(disabled) > 0 {
+		return fmt.Errorf("disabled capabilities: %s", strings.Join(disabled, ","))
+	}
+	return nil
+}
+
+// GetManifestCapabilities returns the capabilities required by the
+// manifest's CapabilityAnnotation, or nil if it has none.
+func (m Manifest) GetManifestCapabilities() []configv1.ClusterVersionCapability {
+	if m.Obj == nil {
+		return nil
+	}
+	annotations := m.Obj.GetAnnotations()
+	required, ok := annotations[CapabilityAnnotation]
+	if !ok || len(required) == 0 {
+		return nil
+	}
+
+	var caps []configv1.ClusterVersionCapability
+	for _, name := range strings.Split(required, "+") {
+		caps = append(caps, configv1.ClusterVersionCapability(name))
+	}
+	return caps
+}
@@ -0,0 +1,154 @@
+package manifest
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func manifestWithAnnotations(annotations map[string]interface{}) Manifest {
+	metadata := map[string]interface{}{}
+	if annotations != nil {
+		metadata["annotations"] = annotations
+	}
+	return Manifest{
+		Obj: &unstructured.Unstructured{
+			Object: map[string]interface{}{
+				"metadata": metadata,
+			},
+		},
+	}
+}
+
+func TestSyncWave(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]interface{}
+		want        int
+	}{
+		{name: "no annotations", want: 0},
+		{name: "unset", annotations: map[string]interface{}{}, want: 0},
+		{name: "zero", annotations: map[string]interface{}{SyncWaveAnnotation: "0"}, want: 0},
+		{name: "positive", annotations: map[string]interface{}{SyncWaveAnnotation: "5"}, want: 5},
+		{name: "negative", annotations: map[string]interface{}{SyncWaveAnnotation: "-1"}, want: -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := manifestWithAnnotations(tt.annotations)
+			assert.Equal(t, tt.want, m.SyncWave())
+		})
+	}
+}
+
+func TestHook(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]interface{}
+		want        HookType
+	}{
+		{name: "no annotations", want: HookSync},
+		{name: "unset", annotations: map[string]interface{}{}, want: HookSync},
+		{name: "pre-sync", annotations: map[string]interface{}{HookAnnotation: "PreSync"}, want: HookPreSync},
+		{name: "sync", annotations: map[string]interface{}{HookAnnotation: "Sync"}, want: HookSync},
+		{name: "post-sync", annotations: map[string]interface{}{HookAnnotation: "PostSync"}, want: HookPostSync},
+		{name: "sync-fail", annotations: map[string]interface{}{HookAnnotation: "SyncFail"}, want: HookSyncFail},
+		{name: "unrecognized", annotations: map[string]interface{}{HookAnnotation: "Bogus"}, want: HookSync},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := manifestWithAnnotations(tt.annotations)
+			assert.Equal(t, tt.want, m.Hook())
+		})
+	}
+}
+
+func TestParseManifestsOrdering(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		wantErr string
+	}{{
+		name: "valid sync-wave and hook",
+		raw: `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: a-config
+  namespace: default
+  annotations:
+    release.openshift.io/sync-wave: "3"
+    release.openshift.io/hook: PreSync
+`,
+	}, {
+		name: "non-integer sync-wave",
+		raw: `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: a-config
+  namespace: default
+  annotations:
+    release.openshift.io/sync-wave: "not-a-number"
+`,
+		wantErr: `Kind: "ConfigMap" Namespace: "default" Name: "a-config"`,
+	}, {
+		name: "unrecognized hook",
+		raw: `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: a-config
+  namespace: default
+  annotations:
+    release.openshift.io/hook: Eventually
+`,
+		wantErr: `Kind: "ConfigMap" Namespace: "default" Name: "a-config"`,
+	}}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ParseManifests(strings.NewReader(test.raw))
+			if len(test.wantErr) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Fatalf("got %v, wanted an error containing %q", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestSortManifests(t *testing.T) {
+	withHook := func(wave int, hook HookType) Manifest {
+		annotations := map[string]interface{}{}
+		if wave != 0 {
+			annotations[SyncWaveAnnotation] = strconv.Itoa(wave)
+		}
+		if hook != "" {
+			annotations[HookAnnotation] = string(hook)
+		}
+		return manifestWithAnnotations(annotations)
+	}
+
+	noHook1 := withHook(0, "")
+	noHook2 := withHook(1, "")
+	preSync := withHook(0, HookPreSync)
+	postSyncWave2 := withHook(2, HookPostSync)
+	postSyncWave1 := withHook(1, HookPostSync)
+	syncFail := withHook(0, HookSyncFail)
+
+	got := SortManifests([]Manifest{postSyncWave2, noHook1, postSyncWave1, syncFail, noHook2, preSync})
+
+	want := [][]Manifest{
+		{preSync},
+		{noHook1, noHook2},
+		{postSyncWave1, postSyncWave2},
+		{syncFail},
+	}
+	assert.Equal(t, want, got)
+}